@@ -21,7 +21,6 @@ import (
 	rreconcile "github.com/fluxcd/pkg/runtime/reconcile"
 	"github.com/mandelsoft/vfs/pkg/osfs"
 	"github.com/mandelsoft/vfs/pkg/projectionfs"
-	"github.com/tetratelabs/wazero"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -45,20 +44,22 @@ import (
 	"github.com/open-component-model/ocm-controller/pkg/component"
 	"github.com/open-component-model/ocm-controller/pkg/event"
 	"github.com/open-component-model/ocm-controller/pkg/ocm"
+	"github.com/open-component-model/ocm-controller/pkg/ocm/access"
 	"github.com/open-component-model/ocm-controller/pkg/snapshot"
+	"github.com/open-component-model/ocm-controller/pkg/status"
+	"github.com/open-component-model/ocm-controller/pkg/wasm"
 	"github.com/open-component-model/ocm/pkg/common"
-	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/localblob"
-	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
-	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociblob"
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/download/handlers/dirtree"
 
 	ocmreg "github.com/open-component-model/ocm/pkg/contexts/ocm/repositories/ocireg"
-	"github.com/wapc/wapc-go"
-	wazeroEngine "github.com/wapc/wapc-go/engines/wazero"
 )
 
-// ResourceReconciler reconciles a Resource object
+// ResourceReconciler reconciles a Resource object.
+//
+// Resource is still served at v1alpha1; see the TODO(api-v1) on
+// pkg/status's sub-conditions for what's missing to promote it to
+// delivery.ocm.software/v1.
 type ResourceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
@@ -66,6 +67,19 @@ type ResourceReconciler struct {
 	OCMClient      ocm.Contract
 	Cache          cache.Cache
 	SnapshotWriter snapshot.Writer
+	// AccessResolver resolves a resource's access spec to a reference for
+	// the "get/resource" host call. Defaults to access.NewDefaultRegistry()
+	// when nil, so callers only need to set it to plug in additional
+	// access methods.
+	AccessResolver *access.Registry
+	// DirtreeCache, if set, short-circuits dirtree.Download for a resource
+	// whose blob digest hasn't changed since it was last downloaded. Nil
+	// disables caching and every reconcile downloads into a throwaway
+	// tempdir, as before.
+	DirtreeCache *cache.DirtreeCache
+	// DownloadLimiter bounds how many dirtree.Download calls run at once
+	// across all ResourceReconciler workers. Nil means unbounded.
+	DownloadLimiter *cache.DownloadLimiter
 }
 
 // +kubebuilder:rbac:groups=delivery.ocm.software,resources=resources,verbs=get;list;watch;create;update;patch;delete
@@ -161,9 +175,14 @@ func (r *ResourceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 			event.New(r.EventRecorder, obj, eventv1.EventSeverityInfo, "Reconciliation success", nil)
 		}
 
-		// Set status observed generation option if the object is stalled or ready.
+		// ObservedGeneration is kept in lockstep with Generation on every
+		// status write, not only once the object settles into Ready or
+		// Stalled, so that kstatus.Compute (and anything calling it, e.g.
+		// flux's generic waiters) never reports Current against a status
+		// that hasn't caught up with the latest spec change yet.
+		obj.Status.ObservedGeneration = obj.Generation
+
 		if conditions.IsStalled(obj) || conditions.IsReady(obj) {
-			obj.Status.ObservedGeneration = obj.Generation
 			event.New(r.EventRecorder, obj, eventv1.EventSeverityInfo, fmt.Sprintf("Reconciliation finished, next run in %s", obj.GetRequeueAfter()),
 				map[string]string{v1alpha1.GroupVersion.Group + "/resource_version": obj.Status.LastAppliedResourceVersion})
 		}
@@ -207,6 +226,7 @@ func (r *ResourceReconciler) reconcile(ctx context.Context, obj *v1alpha1.Resour
 	if err := r.Get(ctx, obj.Spec.SourceRef.GetObjectKey(), componentVersion); err != nil {
 		err = fmt.Errorf("failed to get component version: %w", err)
 		conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.GetResourceFailedReason, err.Error())
+		status.MarkSourceNotReady(obj, v1alpha1.GetResourceFailedReason, err.Error())
 		event.New(r.EventRecorder, obj, eventv1.EventSeverityError, err.Error(), nil)
 		return ctrl.Result{}, err
 	}
@@ -215,66 +235,53 @@ func (r *ResourceReconciler) reconcile(ctx context.Context, obj *v1alpha1.Resour
 	if err != nil {
 		err = fmt.Errorf("failed to create authenticated client: %w", err)
 		conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.AuthenticatedContextCreationFailedReason, err.Error())
+		status.MarkSourceNotReady(obj, v1alpha1.AuthenticatedContextCreationFailedReason, err.Error())
 	}
 
 	cv, err := r.OCMClient.GetComponentVersion(ctx, octx, componentVersion)
 	if err != nil {
+		status.MarkSourceNotReady(obj, v1alpha1.GetResourceFailedReason, err.Error())
 		return ctrl.Result{}, err
 	}
 
 	res, err := cv.GetResource(ocmmetav1.NewIdentity(obj.Spec.SourceRef.ResourceRef.Name))
 	if err != nil {
+		status.MarkSourceNotReady(obj, v1alpha1.GetResourceFailedReason, err.Error())
 		return ctrl.Result{}, err
 	}
 
-	dir, err := os.MkdirTemp("", "wasm-tmp-")
+	status.MarkSourceReady(obj)
+
+	dir, err := r.resolveResourceDir(ctx, componentVersion, obj, res)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 	defer os.RemoveAll(dir)
 
-	tmpfs, err := projectionfs.New(osfs.New(), dir)
-	if err != nil {
-		os.Remove(dir)
-	}
-
-	_, _, err = dirtree.New().Download(common.NewPrinter(os.Stdout), res, "", tmpfs)
-	if err != nil {
-		return ctrl.Result{}, err
+	resolver := r.AccessResolver
+	if resolver == nil {
+		resolver = access.NewDefaultRegistry()
 	}
 
-	filepath.WalkDir(dir, func(p string, d os.DirEntry, e error) error {
-		if d.IsDir() {
-			return nil
-		}
-		data, err := os.ReadFile(p)
-		if err != nil {
-			return err
-		}
-		decode := scheme.Codecs.UniversalDeserializer().Decode
-		obj, _, err := decode(data, nil, nil)
-		b, err := json.Marshal(obj)
-		if err != nil {
-			return err
-		}
-		return os.WriteFile(p, b, fs.ModeType)
-	})
-
-	engine := wazeroEngine.Engine()
+	host := makeHost(r.EventRecorder, obj, cv, dir, resolver)
+	wasmRuntime := wasm.New(dir, wasm.DefaultConfig())
 
 	for _, md := range obj.Spec.Middleware {
-		mdRepo, err := octx.RepositoryForSpec(ocmreg.NewRepositorySpec(md.Registry, nil))
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-		defer mdRepo.Close()
+		middlewareCV := cv
+		if md.Registry != "" && md.Component != "" {
+			mdRepo, err := octx.RepositoryForSpec(ocmreg.NewRepositorySpec(md.Registry, nil))
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			defer mdRepo.Close()
 
-		component := strings.Split(md.Component, ":")
-		middlewareCV, err := mdRepo.LookupComponentVersion(component[0], component[1])
-		if err != nil {
-			return ctrl.Result{}, err
+			component := strings.Split(md.Component, ":")
+			middlewareCV, err = mdRepo.LookupComponentVersion(component[0], component[1])
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			defer middlewareCV.Close()
 		}
-		defer middlewareCV.Close()
 
 		res, err := middlewareCV.GetResource(ocmmetav1.NewIdentity(md.Name))
 		if err != nil {
@@ -291,33 +298,20 @@ func (r *ResourceReconciler) reconcile(ctx context.Context, obj *v1alpha1.Resour
 			return ctrl.Result{}, err
 		}
 
-		module, err := engine.New(ctx, makeHost(cv, dir), data, &wapc.ModuleConfig{
-			Logger: wapc.PrintlnLogger,
-			Stdout: os.Stdout,
-			Stderr: os.Stderr,
-		})
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-		defer module.Close(ctx)
-
-		module.(*wazeroEngine.Module).WithConfig(func(config wazero.ModuleConfig) wazero.ModuleConfig {
-			conf := wazero.NewFSConfig().WithDirMount(dir, "/data")
-			return config.WithFSConfig(conf).WithSysWalltime()
-		})
-
-		instance, err := module.Instantiate(ctx)
-		if err != nil {
-			return ctrl.Result{}, err
+		kind := wasm.KindWAPC
+		if md.Kind == v1alpha1.MiddlewareKindWASI {
+			kind = wasm.KindWASI
 		}
-		defer instance.Close(ctx)
 
-		_, err = instance.Invoke(ctx, "handler", md.Values.Raw)
-		if err != nil {
+		if _, err := wasmRuntime.Invoke(ctx, host.Func(), wasm.Source{Kind: kind, Data: data}, md.Values.Raw); err != nil {
+			err = fmt.Errorf("failed to run middleware %q: %w", md.Name, err)
+			status.MarkMiddlewareNotReady(obj, v1alpha1.MiddlewareFailedReason, err.Error())
 			return ctrl.Result{}, err
 		}
 	}
 
+	status.MarkMiddlewareReady(obj)
+
 	version := "latest"
 	if obj.Spec.SourceRef.GetVersion() != "" {
 		version = obj.Spec.SourceRef.GetVersion()
@@ -361,9 +355,12 @@ func (r *ResourceReconciler) reconcile(ctx context.Context, obj *v1alpha1.Resour
 
 	_, err = r.SnapshotWriter.Write(ctx, obj, dir, identity)
 	if err != nil {
+		status.MarkSnapshotNotReady(obj, v1alpha1.SnapshotWriteFailedReason, err.Error())
 		return ctrl.Result{}, err
 	}
 
+	status.MarkSnapshotReady(obj)
+
 	logger.Info("successfully pushed snapshot for resource", "resource", obj.Spec.SourceRef.Name)
 
 	obj.Status.LastAppliedResourceVersion = obj.Spec.SourceRef.GetVersion()
@@ -380,6 +377,103 @@ func (r *ResourceReconciler) reconcile(ctx context.Context, obj *v1alpha1.Resour
 	return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
 }
 
+// resolveResourceDir returns a private, caller-owned directory holding
+// res's downloaded, JSON-decoded manifests - the caller mounts it for
+// middleware and passes it to SnapshotWriter, and must remove it once
+// done. Even when r.DirtreeCache has an entry for res's current digest,
+// the caller gets a fresh copy of it rather than the cached directory
+// itself: the cache only ever hands out pristine copies, so middleware
+// mutating its mount on one reconcile can't taint the cache entry or leak
+// into a concurrent or later reconcile that resolves to the same key.
+func (r *ResourceReconciler) resolveResourceDir(ctx context.Context, componentVersion *v1alpha1.ComponentVersion, obj *v1alpha1.Resource, res ocmv1.ResourceAccess) (string, error) {
+	download := func() (string, error) {
+		if err := r.DownloadLimiter.Acquire(ctx); err != nil {
+			return "", fmt.Errorf("failed to acquire download slot: %w", err)
+		}
+		defer r.DownloadLimiter.Release()
+
+		dir, err := os.MkdirTemp("", "wasm-tmp-")
+		if err != nil {
+			return "", err
+		}
+
+		tmpfs, err := projectionfs.New(osfs.New(), dir)
+		if err != nil {
+			os.RemoveAll(dir)
+
+			return "", err
+		}
+
+		if _, _, err := dirtree.New().Download(common.NewPrinter(os.Stdout), res, "", tmpfs); err != nil {
+			os.RemoveAll(dir)
+
+			return "", err
+		}
+
+		if err := decodeManifestsToJSON(dir); err != nil {
+			os.RemoveAll(dir)
+
+			return "", err
+		}
+
+		return dir, nil
+	}
+
+	if r.DirtreeCache == nil {
+		return download()
+	}
+
+	cacheKey := cache.Key(componentVersion.GetName(), componentVersion.Status.ReconciledVersion, obj.Spec.SourceRef.ResourceRef.Name, resourceDigest(res))
+
+	dir, hit, err := r.DirtreeCache.Resolve(cacheKey, download)
+	if err != nil {
+		return "", err
+	}
+	if hit {
+		event.New(r.EventRecorder, obj, eventv1.EventSeverityInfo, "reusing cached resource download", nil)
+	} else {
+		event.New(r.EventRecorder, obj, eventv1.EventSeverityInfo, "downloaded resource, no cache hit", nil)
+	}
+
+	return dir, nil
+}
+
+// decodeManifestsToJSON rewrites every file dirtree.Download wrote under
+// dir from its native YAML encoding to JSON, since the WASM middleware
+// modules expect to read JSON.
+func decodeManifestsToJSON(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d os.DirEntry, e error) error {
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		decode := scheme.Codecs.UniversalDeserializer().Decode
+		obj, _, err := decode(data, nil, nil)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(p, b, fs.ModeType)
+	})
+}
+
+// resourceDigest returns the content digest of res's blob, or "" if it
+// doesn't have one yet, for use as part of a DirtreeCache key.
+func resourceDigest(res ocmv1.ResourceAccess) string {
+	resMeta := res.Meta()
+	if resMeta == nil || resMeta.Digest == nil {
+		return ""
+	}
+
+	return resMeta.Digest.Value
+}
+
 // this function will enqueue a reconciliation for any snapshot which is referenced
 // in the .spec.sourceRef or spec.configRef field of a Localization
 func (r *ResourceReconciler) findObjects(key string) func(client.Object) []reconcile.Request {
@@ -411,59 +505,17 @@ func (r *ResourceReconciler) findObjects(key string) func(client.Object) []recon
 	}
 }
 
-func makeHost(cv ocmv1.ComponentVersionAccess, dir string) func(ctx context.Context, binding, namespace, operation string, payload []byte) ([]byte, error) {
-	return func(ctx context.Context, binding, namespace, operation string, payload []byte) ([]byte, error) {
-		if binding != "ocm.software" {
-			return nil, errors.New("unrecognised binding")
-		}
-		switch namespace {
-		case "get":
-			switch operation {
-			case "resource":
-				res, err := cv.GetResource(ocmmetav1.NewIdentity(string(payload)))
-				if err != nil {
-					return nil, err
-				}
-
-				ref, err := getReference(cv.GetContext(), res)
-				if err != nil {
-					return nil, err
-				}
-
-				return []byte(ref), nil
-			}
-		}
-		return nil, errors.New("unrecognised namespace")
+// makeHost builds the host ABI implementation middleware modules for obj
+// are invoked against. The heavy lifting lives in pkg/wasm so it can be
+// reused outside the controller; resolver is an access.Registry (or
+// anything satisfying wasm.Resolver) so callers can plug in access
+// methods beyond the three the controller ships with.
+func makeHost(recorder kuberecorder.EventRecorder, obj *v1alpha1.Resource, cv ocmv1.ComponentVersionAccess, dir string, resolver wasm.Resolver) *wasm.Host {
+	return &wasm.Host{
+		CV:       cv,
+		Dir:      dir,
+		Resolver: resolver,
+		Recorder: recorder,
+		Object:   obj,
 	}
 }
-
-func getReference(octx ocmv1.Context, res ocmv1.ResourceAccess) (string, error) {
-	accSpec, err := res.Access()
-	if err != nil {
-		return "", err
-	}
-
-	var (
-		ref    string
-		refErr error
-	)
-
-	for ref == "" && refErr == nil {
-		switch x := accSpec.(type) {
-		case *ociartifact.AccessSpec:
-			ref = x.ImageReference
-		case *ociblob.AccessSpec:
-			ref = fmt.Sprintf("%s@%s", x.Reference, x.Digest)
-		case *localblob.AccessSpec:
-			if x.GlobalAccess == nil {
-				refErr = errors.New("cannot determine image digest")
-			} else {
-				accSpec, refErr = octx.AccessSpecForSpec(x.GlobalAccess)
-			}
-		default:
-			refErr = errors.New("cannot determine access spec type")
-		}
-	}
-
-	return ref, nil
-}
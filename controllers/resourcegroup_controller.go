@@ -0,0 +1,284 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/runtime/patch"
+	rreconcile "github.com/fluxcd/pkg/runtime/reconcile"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kuberecorder "k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/event"
+	"github.com/open-component-model/ocm-controller/pkg/resourcegroup"
+)
+
+// ResourceGroupReconciler reconciles a ResourceGroup object
+type ResourceGroupReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	kuberecorder.EventRecorder
+	Impersonator resourcegroup.Impersonator
+}
+
+// +kubebuilder:rbac:groups=delivery.ocm.software,resources=resourcegroups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=delivery.ocm.software,resources=resourcegroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=delivery.ocm.software,resources=resourcegroups/finalizers,verbs=update
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ResourceGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	const (
+		resourceKey = ".metadata.resourceGroupInput"
+	)
+
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.ResourceGroup{}, resourceKey, func(rawObj client.Object) []string {
+		rg := rawObj.(*v1alpha1.ResourceGroup)
+		names := make([]string, 0, len(rg.Spec.Inputs))
+		for _, in := range rg.Spec.Inputs {
+			names = append(names, in.SourceRef.Name)
+		}
+
+		return names
+	}); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ResourceGroup{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &v1alpha1.Resource{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjects(resourceKey)),
+		).
+		Complete(r)
+}
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *ResourceGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	logger := log.FromContext(ctx).WithName("resourcegroup-controller")
+
+	obj := &v1alpha1.ResourceGroup{}
+	if err = r.Client.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("failed to get resource group object: %w", err)
+	}
+
+	if obj.Spec.Suspend {
+		logger.Info("resource group object suspended")
+		return result, nil
+	}
+
+	patchHelper, err := patch.NewHelper(obj, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create patch helper: %w", err)
+	}
+
+	defer func() {
+		if condition := conditions.Get(obj, meta.StalledCondition); condition != nil && condition.Status == metav1.ConditionTrue {
+			conditions.Delete(obj, meta.ReconcilingCondition)
+		}
+
+		if result.RequeueAfter == obj.GetRequeueAfter() && !result.Requeue && err == nil {
+			conditions.Delete(obj, meta.ReconcilingCondition)
+
+			if ready := conditions.Get(obj, meta.ReadyCondition); ready != nil && ready.Status == metav1.ConditionFalse && !conditions.IsStalled(obj) {
+				err = errors.New(conditions.GetMessage(obj, meta.ReadyCondition))
+			}
+		}
+
+		if conditions.IsReconciling(obj) {
+			reconciling := conditions.Get(obj, meta.ReconcilingCondition)
+			reconciling.Reason = meta.ProgressingWithRetryReason
+			conditions.Set(obj, reconciling)
+		}
+
+		if !conditions.IsReconciling(obj) && !conditions.IsStalled(obj) &&
+			err == nil && result.RequeueAfter == obj.GetRequeueAfter() {
+			conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "Reconciliation success")
+			event.New(r.EventRecorder, obj, eventv1.EventSeverityInfo, "Reconciliation success", nil)
+		}
+
+		obj.Status.ObservedGeneration = obj.Generation
+
+		if perr := patchHelper.Patch(ctx, obj); perr != nil {
+			err = errors.Join(err, perr)
+		}
+	}()
+
+	rreconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress")
+
+	conditions.Delete(obj, meta.StalledCondition)
+
+	return r.reconcile(ctx, obj)
+}
+
+func (r *ResourceGroupReconciler) reconcile(ctx context.Context, obj *v1alpha1.ResourceGroup) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithName("resourcegroup-controller")
+
+	inputs, err := r.resolveInputs(ctx, obj)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve inputs: %w", err)
+		conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.ResourceGroupInputsNotReadyReason, err.Error())
+		event.New(r.EventRecorder, obj, eventv1.EventSeverityError, err.Error(), nil)
+
+		return ctrl.Result{}, err
+	}
+
+	ordered, err := resourcegroup.TopoSort(obj.Spec.Resources)
+	if err != nil {
+		err = fmt.Errorf("failed to order resources by dependsOn: %w", err)
+		conditions.MarkStalled(obj, v1alpha1.ResourceGroupDependencyCycleReason, err.Error())
+		conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.ResourceGroupDependencyCycleReason, err.Error())
+		event.New(r.EventRecorder, obj, eventv1.EventSeverityError, err.Error(), nil)
+
+		return ctrl.Result{}, nil
+	}
+
+	applier, err := r.Impersonator.ClientFor(ctx, obj.GetNamespace(), obj.Spec.ServiceAccountName)
+	if err != nil {
+		err = fmt.Errorf("failed to impersonate service account %q: %w", obj.Spec.ServiceAccountName, err)
+		conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.ResourceGroupImpersonationFailedReason, err.Error())
+		event.New(r.EventRecorder, obj, eventv1.EventSeverityError, err.Error(), nil)
+
+		return ctrl.Result{}, err
+	}
+
+	for _, res := range ordered {
+		rendered, err := resourcegroup.Render(res.Resource, inputs)
+		if err != nil {
+			err = fmt.Errorf("failed to render resource %q: %w", res.Name, err)
+			conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.ResourceGroupTemplateFailedReason, err.Error())
+			event.New(r.EventRecorder, obj, eventv1.EventSeverityError, err.Error(), nil)
+
+			return ctrl.Result{}, err
+		}
+
+		applyCommonMetadata(rendered, obj.Spec.CommonMetadata)
+
+		if err := applier.Patch(ctx, rendered, client.Apply, client.ForceOwnership, client.FieldOwner("resourcegroup-controller")); err != nil {
+			err = fmt.Errorf("failed to apply resource %q: %w", res.Name, err)
+			conditions.MarkFalse(obj, meta.ReadyCondition, v1alpha1.ResourceGroupApplyFailedReason, err.Error())
+			event.New(r.EventRecorder, obj, eventv1.EventSeverityError, err.Error(), nil)
+
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("applied resource group object", "resource", res.Name, "gvk", rendered.GroupVersionKind())
+	}
+
+	conditions.Delete(obj, meta.ReadyCondition)
+	obj.Status.ObservedGeneration = obj.GetGeneration()
+
+	logger.Info("successfully reconciled resource group", "name", obj.GetName())
+
+	return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
+}
+
+// resolveInputs reads the snapshot produced by each of obj.Spec.Inputs'
+// source Resources and returns them keyed by input name, ready to be
+// substituted into obj.Spec.Resources templates.
+func (r *ResourceGroupReconciler) resolveInputs(ctx context.Context, obj *v1alpha1.ResourceGroup) (map[string]any, error) {
+	inputs := make(map[string]any, len(obj.Spec.Inputs))
+
+	for _, in := range obj.Spec.Inputs {
+		source := &v1alpha1.Resource{}
+		key := types.NamespacedName{Name: in.SourceRef.Name, Namespace: obj.GetNamespace()}
+		if in.SourceRef.Namespace != "" {
+			key.Namespace = in.SourceRef.Namespace
+		}
+
+		if err := r.Get(ctx, key, source); err != nil {
+			return nil, fmt.Errorf("failed to get input %q: %w", in.Name, err)
+		}
+
+		if !conditions.IsReady(source) {
+			return nil, fmt.Errorf("input %q is not ready", in.Name)
+		}
+
+		values, err := resourcegroup.ReadSnapshotValues(ctx, r.Client, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot for input %q: %w", in.Name, err)
+		}
+
+		inputs[in.Name] = values
+	}
+
+	return inputs, nil
+}
+
+func applyCommonMetadata(obj *unstructured.Unstructured, common *v1alpha1.CommonMetadata) {
+	if common == nil {
+		return
+	}
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range common.Labels {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for k, v := range common.Annotations {
+		annotations[k] = v
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// this function will enqueue a reconciliation for any ResourceGroup which
+// references the changed Resource as one of its inputs.
+func (r *ResourceGroupReconciler) findObjects(key string) func(client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		groups := &v1alpha1.ResourceGroupList{}
+		if err := r.List(context.TODO(), groups, &client.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector(key, obj.GetName()),
+			Namespace:     obj.GetNamespace(),
+		}); err != nil {
+			return []reconcile.Request{}
+		}
+
+		requests := make([]reconcile.Request, len(groups.Items))
+		for i, item := range groups.Items {
+			requests[i] = reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      item.GetName(),
+					Namespace: item.GetNamespace(),
+				},
+			}
+		}
+
+		return requests
+	}
+}
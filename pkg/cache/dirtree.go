@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DirtreeCache is a content-addressed, on-disk cache for the directory
+// dirtree.Download produces for a resource. It lets ResourceReconciler
+// skip a re-download (and the decode pass that follows it) when a
+// resource's blob digest hasn't changed since the last reconcile.
+//
+// Every entry in the cache is pristine: callers never get the cached
+// directory itself, only a private copy of it (see Resolve), so that
+// mutations a caller makes to its copy - e.g. WASM middleware writing
+// scratch files into the mounted directory - never leak into the cache or
+// into a different caller's copy.
+type DirtreeCache struct {
+	root string
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock is a per-key lock that removes itself from DirtreeCache.locks
+// once nothing is waiting on it, so a long-running controller doesn't
+// accumulate one mutex per distinct key it has ever seen.
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewDirtreeCache returns a DirtreeCache rooted at root, creating it if it
+// does not already exist.
+func NewDirtreeCache(root string) (*DirtreeCache, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dirtree cache root %q: %w", root, err)
+	}
+
+	return &DirtreeCache{root: root}, nil
+}
+
+// Key derives a cache key for a resource's downloaded directory from the
+// component, its version, the resource's identity, and the resource's
+// blob digest. Any part of the identity changing - including the digest,
+// which changes whenever the underlying blob does - yields a different
+// key, so a stale entry is never served.
+func Key(component, version, resourceIdentity, digest string) string {
+	sum := sha256.Sum256([]byte(component + "/" + version + "/" + resourceIdentity + "/" + digest))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Resolve returns a private, caller-owned copy of the cached directory for
+// key - the caller is free to mutate or remove it. If key is not yet
+// cached, download is called to produce one, which is then adopted into
+// the cache as key's new pristine entry before a copy of it is handed
+// back. hit reports whether an existing entry was reused.
+//
+// Concurrent calls for the same key are serialized, so only one caller
+// ever downloads for a given key and no caller can observe the moment
+// between an old entry being cleared and its replacement being adopted.
+// Calls for different keys proceed concurrently.
+func (c *DirtreeCache) Resolve(key string, download func() (string, error)) (dir string, hit bool, err error) {
+	unlock := c.lock(key)
+	defer unlock()
+
+	path := filepath.Join(c.root, key)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		ObserveCacheHit()
+
+		scratch, err := copyDir(path)
+
+		return scratch, true, err
+	}
+
+	ObserveCacheMiss()
+
+	src, err := download()
+	if err != nil {
+		return "", false, err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		os.RemoveAll(src)
+
+		return "", false, fmt.Errorf("failed to clear stale cache entry: %w", err)
+	}
+
+	if err := os.Rename(src, path); err != nil {
+		os.RemoveAll(src)
+
+		return "", false, fmt.Errorf("failed to adopt %q into dirtree cache: %w", src, err)
+	}
+
+	scratch, err := copyDir(path)
+
+	return scratch, false, err
+}
+
+// lock returns an unlock func for key, blocking until any other caller
+// holding it has released it. Once the returned func is called and no
+// other caller is waiting on key, its entry in c.locks is removed.
+func (c *DirtreeCache) lock(key string) func() {
+	c.mu.Lock()
+	if c.locks == nil {
+		c.locks = make(map[string]*keyLock)
+	}
+	kl, ok := c.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		c.locks[key] = kl
+	}
+	kl.refs++
+	c.mu.Unlock()
+
+	kl.mu.Lock()
+
+	return func() {
+		kl.mu.Unlock()
+
+		c.mu.Lock()
+		kl.refs--
+		if kl.refs == 0 {
+			delete(c.locks, key)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// copyDir returns a fresh temp directory containing a recursive copy of
+// src, for a Resolve caller to own privately.
+func copyDir(src string) (string, error) {
+	dst, err := os.MkdirTemp("", "dirtree-scratch-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+
+	if err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(target, data, 0o644)
+	}); err != nil {
+		os.RemoveAll(dst)
+
+		return "", fmt.Errorf("failed to copy cached directory: %w", err)
+	}
+
+	return dst, nil
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKey_StableAndDigestSensitive(t *testing.T) {
+	a := Key("component", "v1.0.0", "resource", "sha256:abc")
+	b := Key("component", "v1.0.0", "resource", "sha256:abc")
+	if a != b {
+		t.Fatal("expected Key to be deterministic for identical inputs")
+	}
+
+	c := Key("component", "v1.0.0", "resource", "sha256:def")
+	if a == c {
+		t.Fatal("expected a different digest to yield a different key")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %q: %v", path, err)
+	}
+}
+
+func TestDirtreeCache_ResolveDownloadsOnMiss(t *testing.T) {
+	c, err := NewDirtreeCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var downloads int32
+	download := func() (string, error) {
+		atomic.AddInt32(&downloads, 1)
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "manifest.json"), "original")
+
+		return dir, nil
+	}
+
+	dir, hit, err := c.Resolve("key", download)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hit {
+		t.Fatal("expected a miss on first Resolve")
+	}
+	if downloads != 1 {
+		t.Fatalf("expected exactly one download, got %d", downloads)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil || string(data) != "original" {
+		t.Fatalf("expected resolved copy to contain the downloaded content, got %q, err %v", data, err)
+	}
+}
+
+func TestDirtreeCache_ResolveReusesEntryAsPristineCopy(t *testing.T) {
+	c, err := NewDirtreeCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	download := func() (string, error) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "manifest.json"), "original")
+
+		return dir, nil
+	}
+
+	first, hit, err := c.Resolve("key", download)
+	if err != nil || hit {
+		t.Fatalf("expected a miss and no error on first Resolve, got hit=%v err=%v", hit, err)
+	}
+
+	// Simulate a middleware mutating its mounted copy.
+	writeFile(t, filepath.Join(first, "manifest.json"), "mutated")
+
+	second, hit, err := c.Resolve("key", func() (string, error) {
+		t.Fatal("download should not be called on a cache hit")
+
+		return "", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hit {
+		t.Fatal("expected a hit on second Resolve")
+	}
+	if second == first {
+		t.Fatal("expected Resolve to hand back a distinct private copy, not the same directory")
+	}
+
+	data, err := os.ReadFile(filepath.Join(second, "manifest.json"))
+	if err != nil || string(data) != "original" {
+		t.Fatalf("expected the cached entry to stay pristine despite the caller's mutation, got %q, err %v", data, err)
+	}
+}
+
+func TestDirtreeCache_ResolveSerializesConcurrentSameKeyCalls(t *testing.T) {
+	c, err := NewDirtreeCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var downloads int32
+	download := func() (string, error) {
+		atomic.AddInt32(&downloads, 1)
+		time.Sleep(20 * time.Millisecond)
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "manifest.json"), "original")
+
+		return dir, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := c.Resolve("same-key", download); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if downloads != 1 {
+		t.Fatalf("expected exactly one download for concurrent callers sharing a key, got %d", downloads)
+	}
+}
+
+func TestDirtreeCache_LockDoesNotLeakEntries(t *testing.T) {
+	c, err := NewDirtreeCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	download := func() (string, error) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "manifest.json"), "original")
+
+		return dir, nil
+	}
+
+	for i := 0; i < 50; i++ {
+		key := Key("component", "v1.0.0", "resource", strconv.Itoa(i))
+		if _, _, err := c.Resolve(key, download); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if n := len(c.locks); n != 0 {
+		t.Fatalf("expected no lingering per-key locks once every Resolve call has returned, got %d", n)
+	}
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import "context"
+
+// DownloadLimiter bounds how many OCM fetches (dirtree.Download calls) run
+// at once across all ResourceReconciler workers, so a burst of changed
+// Resources doesn't thrash the API server they're fetching from.
+type DownloadLimiter struct {
+	sem chan struct{}
+}
+
+// NewDownloadLimiter returns a DownloadLimiter that admits at most max
+// concurrent downloads. max <= 0 means unbounded.
+func NewDownloadLimiter(max int) *DownloadLimiter {
+	if max <= 0 {
+		return &DownloadLimiter{}
+	}
+
+	return &DownloadLimiter{sem: make(chan struct{}, max)}
+}
+
+// Acquire blocks until a download slot is free, or ctx is done.
+func (l *DownloadLimiter) Acquire(ctx context.Context) error {
+	if l == nil || l.sem == nil {
+		return nil
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees the slot a prior, successful Acquire took.
+func (l *DownloadLimiter) Release() {
+	if l == nil || l.sem == nil {
+		return
+	}
+
+	<-l.sem
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDownloadLimiter_Unbounded(t *testing.T) {
+	l := NewDownloadLimiter(0)
+
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Release()
+}
+
+func TestDownloadLimiter_BoundsConcurrency(t *testing.T) {
+	l := NewDownloadLimiter(1)
+
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := l.Acquire(ctx); err == nil {
+		t.Fatal("expected the second Acquire to block until the slot is released")
+	}
+
+	l.Release()
+
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error after Release: %v", err)
+	}
+}
+
+func TestDownloadLimiter_NilReceiver(t *testing.T) {
+	var l *DownloadLimiter
+
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Release()
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// dirtreeCacheResult counts DirtreeCache lookups, partitioned into "hit"
+// and "miss", so operators can tell whether --max-concurrent-downloads and
+// the cache are actually saving fetches for their workload.
+var dirtreeCacheResult = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ocm_controller_dirtree_cache_total",
+	Help: "Total number of dirtree cache lookups, partitioned by result (hit or miss).",
+}, []string{"result"})
+
+func init() {
+	metrics.Registry.MustRegister(dirtreeCacheResult)
+}
+
+// ObserveCacheHit and ObserveCacheMiss record a DirtreeCache lookup result.
+func ObserveCacheHit()  { dirtreeCacheResult.WithLabelValues("hit").Inc() }
+func ObserveCacheMiss() { dirtreeCacheResult.WithLabelValues("miss").Inc() }
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package access resolves an OCM resource's access spec to a reference a
+// caller outside the cluster can use to fetch it (an image ref, a digest,
+// or a downloadable URL). It replaces the type switch that used to live
+// inline in the controller with a registry so that new access methods
+// (helm, s3, github, maven, npm, ...) can be supported by registering a
+// Resolver rather than editing the controller.
+package access
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	ocmv1 "github.com/open-component-model/ocm/pkg/contexts/ocm"
+)
+
+// Resolver resolves one access spec type. If the access spec merely
+// indirects to another one (as localblob's GlobalAccess does), it returns
+// next instead of ref and the Registry resolves through it.
+//
+// Some access types (s3, github, maven, npm, ...) have no meaningful
+// reference string to return - the only thing they can offer is a byte
+// stream. A Resolver for one of those should return an error from
+// Resolve directing callers to ResolveStream instead, and additionally
+// implement StreamingResolver so Registry.ResolveStream can reach it.
+type Resolver interface {
+	Resolve(octx ocmv1.Context, accessSpec ocmv1.AccessSpec, res ocmv1.ResourceAccess) (ref string, next ocmv1.AccessSpec, err error)
+}
+
+// StreamingResolver is the optional counterpart to Resolver for access
+// types that can only offer a byte stream rather than a reference. A
+// Resolver implementing this interface can be plugged into a Registry and
+// reached through ResolveStream without the controller needing to know
+// anything about the access type.
+type StreamingResolver interface {
+	ResolveStream(octx ocmv1.Context, accessSpec ocmv1.AccessSpec, res ocmv1.ResourceAccess) (io.ReadCloser, error)
+}
+
+// Registry dispatches resolution to the Resolver registered for an access
+// spec's type. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	resolvers map[string]Resolver
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{resolvers: make(map[string]Resolver)}
+}
+
+// NewDefaultRegistry returns a Registry with the access methods the
+// controller has always supported - ociartifact, ociblob, localblob -
+// already registered.
+func NewDefaultRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register(ociArtifactType, ociArtifactResolver{})
+	reg.Register(ociBlobType, ociBlobResolver{})
+	reg.Register(localBlobType, localBlobResolver{})
+
+	return reg
+}
+
+// Register associates accessType (an access spec's GetType()) with
+// resolver, replacing any resolver previously registered for it.
+func (r *Registry) Register(accessType string, resolver Resolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolvers[accessType] = resolver
+}
+
+// Resolve resolves res's access spec to a reference, following any
+// indirections a Resolver returns until one produces a concrete reference.
+func (r *Registry) Resolve(octx ocmv1.Context, res ocmv1.ResourceAccess) (string, error) {
+	accessSpec, err := res.Access()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		resolver, ok := r.get(accessSpec.GetType())
+		if !ok {
+			return "", fmt.Errorf("no resolver registered for access type %q", accessSpec.GetType())
+		}
+
+		ref, next, err := resolver.Resolve(octx, accessSpec, res)
+		if err != nil {
+			return "", err
+		}
+
+		if next != nil {
+			accessSpec = next
+
+			continue
+		}
+
+		return ref, nil
+	}
+}
+
+// ResolveStream resolves res's access spec the same way Resolve does,
+// following any indirections, but ends on the first resolver in the chain
+// that implements StreamingResolver and returns its stream, instead of
+// requiring a final reference string. This is how access types with no
+// meaningful reference (s3, github, maven, npm, ...) are supported.
+func (r *Registry) ResolveStream(octx ocmv1.Context, res ocmv1.ResourceAccess) (io.ReadCloser, error) {
+	accessSpec, err := res.Access()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		resolver, ok := r.get(accessSpec.GetType())
+		if !ok {
+			return nil, fmt.Errorf("no resolver registered for access type %q", accessSpec.GetType())
+		}
+
+		if streaming, ok := resolver.(StreamingResolver); ok {
+			return streaming.ResolveStream(octx, accessSpec, res)
+		}
+
+		_, next, err := resolver.Resolve(octx, accessSpec, res)
+		if err != nil {
+			return nil, err
+		}
+
+		if next == nil {
+			return nil, fmt.Errorf("access type %q has no streaming resolver", accessSpec.GetType())
+		}
+
+		accessSpec = next
+	}
+}
+
+func (r *Registry) get(accessType string) (Resolver, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resolver, ok := r.resolvers[accessType]
+
+	return resolver, ok
+}
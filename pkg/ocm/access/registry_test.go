@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package access
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	ocmv1 "github.com/open-component-model/ocm/pkg/contexts/ocm"
+)
+
+// streamingOnlyResolver is a Resolver for a hypothetical access type (e.g.
+// s3) that has no meaningful reference string and can only hand back a
+// byte stream, exercising StreamingResolver as an optional capability on
+// top of Resolver.
+type streamingOnlyResolver struct{}
+
+func (streamingOnlyResolver) Resolve(_ ocmv1.Context, _ ocmv1.AccessSpec, _ ocmv1.ResourceAccess) (string, ocmv1.AccessSpec, error) {
+	return "", nil, fmt.Errorf("access type has no reference, use ResolveStream")
+}
+
+func (streamingOnlyResolver) ResolveStream(_ ocmv1.Context, _ ocmv1.AccessSpec, _ ocmv1.ResourceAccess) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("stream")), nil
+}
+
+func TestRegistry_UnknownType(t *testing.T) {
+	reg := NewRegistry()
+
+	_, ok := reg.get("does-not-exist")
+	if ok {
+		t.Fatal("expected no resolver to be registered")
+	}
+}
+
+func TestRegistry_RegisterAndGet(t *testing.T) {
+	reg := NewRegistry()
+	want := ociArtifactResolver{}
+	reg.Register("example", want)
+
+	got, ok := reg.get("example")
+	if !ok {
+		t.Fatal("expected a resolver to be registered")
+	}
+	if got != Resolver(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRegistry_StreamingOnlyResolverRegisters(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("stream-only", streamingOnlyResolver{})
+
+	got, ok := reg.get("stream-only")
+	if !ok {
+		t.Fatal("expected a resolver to be registered")
+	}
+
+	streaming, ok := got.(StreamingResolver)
+	if !ok {
+		t.Fatal("expected the registered resolver to also satisfy StreamingResolver")
+	}
+
+	if _, _, err := got.Resolve(nil, nil, nil); err == nil {
+		t.Fatal("expected Resolve to direct callers to ResolveStream instead of returning a reference")
+	}
+
+	rc, err := streaming.ResolveStream(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil || string(data) != "stream" {
+		t.Fatalf("unexpected stream contents %q, err %v", data, err)
+	}
+}
+
+func TestNewDefaultRegistry_KnownTypes(t *testing.T) {
+	reg := NewDefaultRegistry()
+
+	for _, accessType := range []string{ociArtifactType, ociBlobType, localBlobType} {
+		if _, ok := reg.get(accessType); !ok {
+			t.Fatalf("expected a resolver to be registered for %q", accessType)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package access
+
+import (
+	"errors"
+	"fmt"
+
+	ocmv1 "github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/localblob"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociblob"
+)
+
+const (
+	ociArtifactType = ociartifact.Type
+	ociBlobType     = ociblob.Type
+	localBlobType   = localblob.Type
+)
+
+// ociArtifactResolver resolves an OCI artifact access spec to the image
+// reference it already carries.
+type ociArtifactResolver struct{}
+
+func (ociArtifactResolver) Resolve(_ ocmv1.Context, accessSpec ocmv1.AccessSpec, _ ocmv1.ResourceAccess) (string, ocmv1.AccessSpec, error) {
+	spec, ok := accessSpec.(*ociartifact.AccessSpec)
+	if !ok {
+		return "", nil, fmt.Errorf("expected an ociartifact access spec, got %T", accessSpec)
+	}
+
+	return spec.ImageReference, nil, nil
+}
+
+// ociBlobResolver resolves an OCI blob access spec to "<reference>@<digest>".
+type ociBlobResolver struct{}
+
+func (ociBlobResolver) Resolve(_ ocmv1.Context, accessSpec ocmv1.AccessSpec, _ ocmv1.ResourceAccess) (string, ocmv1.AccessSpec, error) {
+	spec, ok := accessSpec.(*ociblob.AccessSpec)
+	if !ok {
+		return "", nil, fmt.Errorf("expected an ociblob access spec, got %T", accessSpec)
+	}
+
+	return fmt.Sprintf("%s@%s", spec.Reference, spec.Digest), nil, nil
+}
+
+// localBlobResolver indirects through a localblob access spec's
+// GlobalAccess, since a local blob has no meaning outside of the
+// repository it was uploaded alongside.
+type localBlobResolver struct{}
+
+func (localBlobResolver) Resolve(octx ocmv1.Context, accessSpec ocmv1.AccessSpec, _ ocmv1.ResourceAccess) (string, ocmv1.AccessSpec, error) {
+	spec, ok := accessSpec.(*localblob.AccessSpec)
+	if !ok {
+		return "", nil, fmt.Errorf("expected a localblob access spec, got %T", accessSpec)
+	}
+
+	if spec.GlobalAccess == nil {
+		return "", nil, errors.New("cannot determine image digest: no global access configured")
+	}
+
+	next, err := octx.AccessSpecForSpec(spec.GlobalAccess)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "", next, nil
+}
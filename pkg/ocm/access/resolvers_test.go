@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package access
+
+import (
+	"testing"
+
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/localblob"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociblob"
+)
+
+func TestOCIArtifactResolver(t *testing.T) {
+	spec := &ociartifact.AccessSpec{ImageReference: "ghcr.io/acme/app:v1.0.0"}
+
+	ref, next, err := ociArtifactResolver{}.Resolve(nil, spec, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next != nil {
+		t.Fatalf("expected no indirection, got %v", next)
+	}
+	if ref != spec.ImageReference {
+		t.Fatalf("expected ref %q, got %q", spec.ImageReference, ref)
+	}
+}
+
+func TestOCIArtifactResolver_WrongType(t *testing.T) {
+	if _, _, err := (ociArtifactResolver{}).Resolve(nil, &ociblob.AccessSpec{}, nil); err == nil {
+		t.Fatal("expected an error for a mismatched access spec type")
+	}
+}
+
+func TestOCIBlobResolver(t *testing.T) {
+	spec := &ociblob.AccessSpec{Reference: "ghcr.io/acme/app", Digest: "sha256:deadbeef"}
+
+	ref, next, err := ociBlobResolver{}.Resolve(nil, spec, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next != nil {
+		t.Fatalf("expected no indirection, got %v", next)
+	}
+
+	want := "ghcr.io/acme/app@sha256:deadbeef"
+	if ref != want {
+		t.Fatalf("expected ref %q, got %q", want, ref)
+	}
+}
+
+func TestLocalBlobResolver_NoGlobalAccess(t *testing.T) {
+	spec := &localblob.AccessSpec{}
+
+	if _, _, err := (localBlobResolver{}).Resolve(nil, spec, nil); err == nil {
+		t.Fatal("expected an error when GlobalAccess is not set")
+	}
+}
+
+func TestLocalBlobResolver_WrongType(t *testing.T) {
+	if _, _, err := (localBlobResolver{}).Resolve(nil, &ociartifact.AccessSpec{}, nil); err == nil {
+		t.Fatal("expected an error for a mismatched access spec type")
+	}
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Impersonator builds a client that acts as a given ServiceAccount in a
+// given namespace, so the ResourceGroup controller applies objects with
+// the permissions the user granted that ServiceAccount rather than its
+// own, cluster-wide, controller permissions.
+type Impersonator interface {
+	ClientFor(ctx context.Context, namespace, serviceAccountName string) (client.Client, error)
+}
+
+// RESTImpersonator builds impersonating clients from a base rest.Config,
+// the same approach flux controllers use to apply on behalf of a
+// ServiceAccount referenced in a spec.
+type RESTImpersonator struct {
+	Config *rest.Config
+	Scheme *runtime.Scheme
+}
+
+// NewRESTImpersonator returns an Impersonator backed by cfg.
+func NewRESTImpersonator(cfg *rest.Config, scheme *runtime.Scheme) *RESTImpersonator {
+	return &RESTImpersonator{Config: cfg, Scheme: scheme}
+}
+
+// ClientFor returns a client.Client configured to impersonate
+// system:serviceaccount:<namespace>:<serviceAccountName>. If
+// serviceAccountName is empty, it returns a client with the controller's
+// own permissions, matching how other ocm-controller reconcilers behave
+// when no ServiceAccountName is set.
+func (r *RESTImpersonator) ClientFor(_ context.Context, namespace, serviceAccountName string) (client.Client, error) {
+	if serviceAccountName == "" {
+		return client.New(r.Config, client.Options{Scheme: r.Scheme})
+	}
+
+	cfg := rest.CopyConfig(r.Config)
+	cfg.Impersonate = rest.ImpersonationConfig{
+		UserName: fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccountName),
+	}
+
+	return client.New(cfg, client.Options{Scheme: r.Scheme})
+}
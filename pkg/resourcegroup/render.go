@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Render substitutes inputs into tmpl's raw JSON (treated as a Go text
+// template, the same mechanism flux's Kustomization postBuild substitution
+// uses) and decodes the result into an unstructured object ready to apply.
+//
+// Inputs come from whatever OCM component the Resource is consuming, so a
+// template must never splice one in as a bare string: a value containing a
+// `"` would break out of its enclosing JSON string and let the component
+// inject arbitrary sibling keys into the applied object. Templates must
+// instead pipe every input through the "toJson" func, e.g.
+// `"tag": {{ .Inputs.image.Tag | toJson }}` (note no surrounding quotes -
+// toJson supplies them), the same convention Helm uses for the same reason.
+func Render(tmpl *apiextensionsv1.JSON, inputs map[string]any) (*unstructured.Unstructured, error) {
+	if tmpl == nil {
+		return nil, fmt.Errorf("resource template is empty")
+	}
+
+	t, err := template.New("resource").Option("missingkey=error").Funcs(template.FuncMap{
+		"toJson": toJSONString,
+	}).Parse(string(tmpl.Raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resource template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]any{"Inputs": inputs}); err != nil {
+		return nil, fmt.Errorf("failed to render resource template: %w", err)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		return nil, fmt.Errorf("rendered resource is not valid JSON: %w", err)
+	}
+
+	return &unstructured.Unstructured{Object: data}, nil
+}
+
+// toJSONString JSON-encodes v, including the surrounding quotes for a
+// string value, so a template can splice an input into JSON without
+// re-escaping it by hand.
+func toJSONString(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to JSON-encode template value: %w", err)
+	}
+
+	return string(b), nil
+}
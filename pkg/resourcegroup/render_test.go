@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestRender_ToJsonEscapesInjectedInput(t *testing.T) {
+	tmpl := &apiextensionsv1.JSON{Raw: []byte(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {"name": "test"},
+		"data": {"tag": {{ .Inputs.image.Tag | toJson }}}
+	}`)}
+
+	inputs := map[string]any{
+		"image": SnapshotValues{Tag: `v1", "injected": "true`},
+	}
+
+	obj, err := Render(tmpl, inputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, ok := obj.Object["data"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected data map, got %T", obj.Object["data"])
+	}
+
+	if _, exists := data["injected"]; exists {
+		t.Fatal("input value was able to inject a sibling key")
+	}
+
+	if got := data["tag"]; got != `v1", "injected": "true` {
+		t.Fatalf("expected the injection attempt to be treated as a literal tag value, got %q", got)
+	}
+}
+
+func TestRender_NilTemplate(t *testing.T) {
+	if _, err := Render(nil, nil); err == nil {
+		t.Fatal("expected an error for a nil template")
+	}
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// SnapshotValues is what an input's snapshot contributes to template
+// rendering: enough for a resource template to reference the OCI location
+// the snapshot was pushed to without having to read the blob itself.
+type SnapshotValues struct {
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	URL    string `json:"url"`
+}
+
+// ReadSnapshotValues looks up the Snapshot a Resource produced and returns
+// the values a ResourceGroup template is allowed to reference for it.
+func ReadSnapshotValues(ctx context.Context, c client.Client, res *v1alpha1.Resource) (SnapshotValues, error) {
+	snapshotName := res.GetSnapshotName()
+	if snapshotName == "" {
+		return SnapshotValues{}, fmt.Errorf("resource %q has not produced a snapshot yet", res.GetName())
+	}
+
+	snapshot := &v1alpha1.Snapshot{}
+	key := client.ObjectKey{Name: snapshotName, Namespace: res.GetNamespace()}
+	if err := c.Get(ctx, key, snapshot); err != nil {
+		return SnapshotValues{}, fmt.Errorf("failed to get snapshot %q: %w", snapshotName, err)
+	}
+
+	return SnapshotValues{
+		Tag:    snapshot.Spec.Tag,
+		Digest: snapshot.Status.Digest,
+		URL:    snapshot.Status.RepositoryURL,
+	}, nil
+}
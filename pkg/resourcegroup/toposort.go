@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package resourcegroup contains the template rendering, dependency
+// ordering, and apply-time helpers the ResourceGroup controller uses to
+// expand a single OCM resource into many Kubernetes objects.
+package resourcegroup
+
+import (
+	"fmt"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// TopoSort orders resources so that every entry appears after everything
+// listed in its DependsOn, returning an error if DependsOn describes a
+// cycle or references a resource that isn't present.
+func TopoSort(resources []v1alpha1.ResourceTemplate) ([]v1alpha1.ResourceTemplate, error) {
+	byName := make(map[string]v1alpha1.ResourceTemplate, len(resources))
+	for _, res := range resources {
+		byName[res.Name] = res
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(resources))
+	ordered := make([]v1alpha1.ResourceTemplate, 0, len(resources))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected at resource %q", name)
+		}
+
+		res, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown resource %q", name)
+		}
+
+		state[name] = visiting
+		for _, dep := range res.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("resource %q depends on unknown resource %q", name, dep)
+			}
+
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		ordered = append(ordered, res)
+
+		return nil
+	}
+
+	for _, res := range resources {
+		if err := visit(res.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
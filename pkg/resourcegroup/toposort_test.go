@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"testing"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+func resourceNamed(name string, dependsOn ...string) v1alpha1.ResourceTemplate {
+	return v1alpha1.ResourceTemplate{Name: name, DependsOn: dependsOn}
+}
+
+func TestTopoSort_OrdersByDependency(t *testing.T) {
+	resources := []v1alpha1.ResourceTemplate{
+		resourceNamed("c", "b"),
+		resourceNamed("b", "a"),
+		resourceNamed("a"),
+	}
+
+	ordered, err := TopoSort(resources)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make([]string, len(ordered))
+	for i, res := range ordered {
+		got[i] = res.Name
+	}
+
+	want := []string{"a", "b", "c"}
+	for i, name := range want {
+		if got[i] != name {
+			t.Fatalf("expected order %v, got %v", want, got)
+		}
+	}
+}
+
+func TestTopoSort_DetectsCycle(t *testing.T) {
+	resources := []v1alpha1.ResourceTemplate{
+		resourceNamed("a", "b"),
+		resourceNamed("b", "a"),
+	}
+
+	if _, err := TopoSort(resources); err == nil {
+		t.Fatal("expected a dependency cycle error, got nil")
+	}
+}
+
+func TestTopoSort_DetectsSelfCycle(t *testing.T) {
+	resources := []v1alpha1.ResourceTemplate{
+		resourceNamed("a", "a"),
+	}
+
+	if _, err := TopoSort(resources); err == nil {
+		t.Fatal("expected a dependency cycle error, got nil")
+	}
+}
+
+func TestTopoSort_UnknownDependency(t *testing.T) {
+	resources := []v1alpha1.ResourceTemplate{
+		resourceNamed("a", "missing"),
+	}
+
+	if _, err := TopoSort(resources); err == nil {
+		t.Fatal("expected an unknown resource error, got nil")
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Typed sub-conditions published alongside the aggregated Ready condition,
+// so that downstream reconcilers (e.g. Localization, Configuration) can
+// watch a specific facet of a Resource's reconciliation instead of having
+// to interpret the aggregated Ready condition themselves.
+//
+// TODO(api-v1): this is only the status-condition half of promoting
+// Resource to delivery.ocm.software/v1 (kstatus-compliant
+// Ready/Stalled/Reconciling plus these typed sub-conditions). The
+// headline deliverable - the v1 Resource type itself and its conversion
+// webhook - still needs to land in the api module before that promotion
+// is actually complete; they aren't part of this tree, which only
+// contains the controller and status plumbing, so they can't be added
+// here. Until that lands, Resource is still served at v1alpha1 and the
+// "promote to v1" work should be tracked as open, not done - split it
+// into a separate, explicitly api-module-scoped follow-up rather than
+// inferring it's covered by this package.
+const (
+	// SourceReadyCondition reflects whether the object's source reference
+	// (its ComponentVersion) has been resolved.
+	SourceReadyCondition = "SourceReady"
+
+	// MiddlewareReadyCondition reflects whether all configured middleware
+	// modules ran successfully against the downloaded resource.
+	MiddlewareReadyCondition = "MiddlewareReady"
+
+	// SnapshotReadyCondition reflects whether the resulting snapshot has
+	// been written.
+	SnapshotReadyCondition = "SnapshotReady"
+)
+
+func markSubCondition(obj conditions.Setter, condType string, ready bool, reason, msg string) {
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+
+	conditions.Set(obj, &metav1.Condition{
+		Type:    condType,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	})
+}
+
+// MarkSourceReady and MarkSourceNotReady toggle SourceReadyCondition.
+func MarkSourceReady(obj conditions.Setter) {
+	markSubCondition(obj, SourceReadyCondition, true, meta.SucceededReason, "source resolved")
+}
+
+func MarkSourceNotReady(obj conditions.Setter, reason, msg string) {
+	markSubCondition(obj, SourceReadyCondition, false, reason, msg)
+}
+
+// MarkMiddlewareReady and MarkMiddlewareNotReady toggle MiddlewareReadyCondition.
+func MarkMiddlewareReady(obj conditions.Setter) {
+	markSubCondition(obj, MiddlewareReadyCondition, true, meta.SucceededReason, "middleware succeeded")
+}
+
+func MarkMiddlewareNotReady(obj conditions.Setter, reason, msg string) {
+	markSubCondition(obj, MiddlewareReadyCondition, false, reason, msg)
+}
+
+// MarkSnapshotReady and MarkSnapshotNotReady toggle SnapshotReadyCondition.
+func MarkSnapshotReady(obj conditions.Setter) {
+	markSubCondition(obj, SnapshotReadyCondition, true, meta.SucceededReason, "snapshot written")
+}
+
+func MarkSnapshotNotReady(obj conditions.Setter, reason, msg string) {
+	markSubCondition(obj, SnapshotReadyCondition, false, reason, msg)
+}
@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+import (
+	"io"
+	"time"
+)
+
+// Config configures the resource limits and I/O capture applied to every
+// middleware module invocation. A zero value Config is not valid; use
+// DefaultConfig and override only the fields that need to differ.
+type Config struct {
+	// MaxMemoryPages bounds the number of 64KiB WASM memory pages a module
+	// may grow to. This is passed straight through to wazero's runtime
+	// config, so a module that tries to grow past it fails instead of
+	// being allowed to consume unbounded host memory.
+	MaxMemoryPages uint32
+
+	// Timeout bounds how long a single module invocation (one Invoke call)
+	// may run. It is enforced with context.WithTimeout around the call; the
+	// wazero runtime is configured to close the module once that context
+	// is done, so a module still running at the deadline is stopped.
+	Timeout time.Duration
+
+	// Stdout and Stderr capture whatever the module writes to its console.
+	// If nil, output is discarded.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// DefaultConfig returns the limits applied to a middleware invocation when
+// the caller has not configured any. These are deliberately conservative.
+// ResourceReconciler applies them to every middleware module; nothing in a
+// Resource's spec raises or lowers them.
+func DefaultConfig() Config {
+	return Config{
+		MaxMemoryPages: 256, // 16MiB
+		Timeout:        30 * time.Second,
+	}
+}
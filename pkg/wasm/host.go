@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	kuberecorder "k8s.io/client-go/tools/record"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ocmv1 "github.com/open-component-model/ocm/pkg/contexts/ocm"
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+)
+
+// binding is the fixed waPC binding name every ocm-controller host function
+// is registered under; namespace/operation is what differs between calls.
+const binding = "ocm.software"
+
+// Resolver turns a resource's access spec into a reference a caller outside
+// the cluster can use to fetch it (an image ref, a digest, or a
+// downloadable URL). It is the same shape pkg/ocm/access registers
+// per-access-method resolvers against; Host takes one so that registry can
+// be wired in without Host needing to know about individual access types.
+type Resolver interface {
+	Resolve(octx ocmv1.Context, res ocmv1.ResourceAccess) (string, error)
+}
+
+// Host implements the host side of the waPC ABI middleware modules
+// run against: "ocm.software/<namespace>/<operation>". It is deliberately
+// namespaced so new operations can be added without breaking existing
+// middleware that only knows about older ones. Only waPC modules can call
+// these operations; a WASI module is run without them and sees only
+// wasi_snapshot_preview1 and its "/data" mount.
+type Host struct {
+	// CV is the component version the resource being processed belongs to;
+	// "get/resource" and "list/resources" resolve against it.
+	CV ocmv1.ComponentVersionAccess
+
+	// Dir is the projection filesystem root mounted into the module as
+	// "/data"; "get/blob" downloads into it and "fs/read", "fs/write" are
+	// scoped to it.
+	Dir string
+
+	// Resolver resolves a resource's access spec to a caller-usable
+	// reference for "get/resource".
+	Resolver Resolver
+
+	// Recorder and Object, if set, let middleware emit Kubernetes events
+	// through "event/emit" instead of only being able to print to stdout.
+	Recorder kuberecorder.EventRecorder
+	Object   runtime.Object
+}
+
+// Func returns the HostFunc to pass to Runtime.Invoke.
+func (h *Host) Func() HostFunc {
+	return h.invoke
+}
+
+func (h *Host) invoke(ctx context.Context, gotBinding, namespace, operation string, payload []byte) ([]byte, error) {
+	if gotBinding != binding {
+		return nil, fmt.Errorf("unrecognised binding %q", gotBinding)
+	}
+
+	switch namespace {
+	case "get":
+		return h.get(ctx, operation, payload)
+	case "list":
+		return h.list(ctx, operation, payload)
+	case "fs":
+		return h.fs(ctx, operation, payload)
+	case "event":
+		return h.event(ctx, operation, payload)
+	default:
+		return nil, fmt.Errorf("unrecognised namespace %q", namespace)
+	}
+}
+
+func (h *Host) get(ctx context.Context, operation string, payload []byte) ([]byte, error) {
+	switch operation {
+	case "resource":
+		res, err := h.CV.GetResource(ocmmetav1.NewIdentity(string(payload)))
+		if err != nil {
+			return nil, err
+		}
+
+		ref, err := h.Resolver.Resolve(h.CV.GetContext(), res)
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(ref), nil
+	case "blob":
+		return h.getBlob(string(payload))
+	case "componentdescriptor":
+		return json.Marshal(h.CV.GetDescriptor())
+	default:
+		return nil, fmt.Errorf("unrecognised get operation %q", operation)
+	}
+}
+
+// getBlob downloads the named resource's blob into Dir and returns its path
+// as the module sees it, under "/data", so middleware can read it from its
+// own mount.
+func (h *Host) getBlob(name string) ([]byte, error) {
+	res, err := h.CV.GetResource(ocmmetav1.NewIdentity(name))
+	if err != nil {
+		return nil, err
+	}
+
+	meth, err := res.AccessMethod()
+	if err != nil {
+		return nil, err
+	}
+	defer meth.Close()
+
+	data, err := meth.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	relPath := filepath.Join("blobs", strings.ReplaceAll(name, "/", "_"))
+	if err := os.MkdirAll(filepath.Join(h.Dir, "blobs"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blobs directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(h.Dir, relPath), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write resource blob: %w", err)
+	}
+
+	return []byte(filepath.Join("/data", relPath)), nil
+}
+
+func (h *Host) list(_ context.Context, operation string, _ []byte) ([]byte, error) {
+	if operation != "resources" {
+		return nil, fmt.Errorf("unrecognised list operation %q", operation)
+	}
+
+	names := make([]string, 0)
+	for _, res := range h.CV.GetDescriptor().Resources {
+		names = append(names, res.Name)
+	}
+
+	return json.Marshal(names)
+}
+
+// fsRequest is the payload shape for "fs/write"; "fs/read" takes a bare
+// path instead since it has nothing else to carry.
+type fsRequest struct {
+	Path string `json:"path"`
+	Data []byte `json:"data"`
+}
+
+func (h *Host) fs(_ context.Context, operation string, payload []byte) ([]byte, error) {
+	switch operation {
+	case "read":
+		path, err := h.scopedPath(string(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		return os.ReadFile(path)
+	case "write":
+		var req fsRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("failed to decode fs/write payload: %w", err)
+		}
+
+		path, err := h.scopedPath(req.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create parent directory: %w", err)
+		}
+
+		return nil, os.WriteFile(path, req.Data, 0o644)
+	default:
+		return nil, fmt.Errorf("unrecognised fs operation %q", operation)
+	}
+}
+
+// scopedPath resolves rel against Dir and refuses to escape it, since Dir
+// is the only filesystem a middleware module is meant to see.
+func (h *Host) scopedPath(rel string) (string, error) {
+	path := filepath.Join(h.Dir, rel)
+	if !strings.HasPrefix(path, filepath.Clean(h.Dir)+string(os.PathSeparator)) && path != filepath.Clean(h.Dir) {
+		return "", fmt.Errorf("path %q escapes the module's mounted directory", rel)
+	}
+
+	return path, nil
+}
+
+type eventRequest struct {
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+func (h *Host) event(_ context.Context, operation string, payload []byte) ([]byte, error) {
+	if operation != "emit" {
+		return nil, fmt.Errorf("unrecognised event operation %q", operation)
+	}
+
+	if h.Recorder == nil || h.Object == nil {
+		return nil, errors.New("host has no recorder configured, cannot emit events")
+	}
+
+	var req eventRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, fmt.Errorf("failed to decode event/emit payload: %w", err)
+	}
+
+	severity := req.Severity
+	if severity == "" {
+		severity = eventv1.EventSeverityInfo
+	}
+
+	reason := "MiddlewareEvent"
+	if severity == eventv1.EventSeverityError {
+		h.Recorder.Event(h.Object, "Warning", reason, req.Message)
+	} else {
+		h.Recorder.Event(h.Object, "Normal", reason, req.Message)
+	}
+
+	return nil, nil
+}
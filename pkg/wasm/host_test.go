@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestHost_ScopedPath_AllowsWithinDir(t *testing.T) {
+	h := &Host{Dir: t.TempDir()}
+
+	path, err := h.scopedPath("sub/file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Dir(path) != filepath.Join(h.Dir, "sub") {
+		t.Fatalf("expected path under %q, got %q", h.Dir, path)
+	}
+}
+
+func TestHost_ScopedPath_RejectsEscape(t *testing.T) {
+	h := &Host{Dir: t.TempDir()}
+
+	if _, err := h.scopedPath("../../etc/passwd"); err == nil {
+		t.Fatal("expected an error escaping the mounted directory")
+	}
+}
+
+func TestHost_FS_WriteThenReadRoundTrips(t *testing.T) {
+	h := &Host{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	req, err := json.Marshal(fsRequest{Path: "sub/file.txt", Data: []byte("hello")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := h.fs(ctx, "write", req); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	got, err := h.fs(ctx, "read", []byte("sub/file.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("expected %q, got %q", "hello", got)
+	}
+}
+
+func TestHost_FS_WriteRejectsPathEscape(t *testing.T) {
+	h := &Host{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	req, err := json.Marshal(fsRequest{Path: "../escaped.txt", Data: []byte("hello")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := h.fs(ctx, "write", req); err == nil {
+		t.Fatal("expected an error escaping the mounted directory")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(h.Dir), "escaped.txt")); err == nil {
+		t.Fatal("escaped.txt should not have been written outside Dir")
+	}
+}
+
+func TestHost_FS_UnrecognisedOperation(t *testing.T) {
+	h := &Host{Dir: t.TempDir()}
+
+	if _, err := h.fs(context.Background(), "delete", nil); err == nil {
+		t.Fatal("expected an error for an unrecognised fs operation")
+	}
+}
+
+func TestHost_Event_NoRecorderErrors(t *testing.T) {
+	h := &Host{}
+
+	payload, err := json.Marshal(eventRequest{Message: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := h.event(context.Background(), "emit", payload); err == nil {
+		t.Fatal("expected an error when no recorder/object is configured")
+	}
+}
+
+func TestHost_Event_EmitsThroughRecorder(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	h := &Host{Recorder: recorder, Object: &corev1.ConfigMap{}}
+
+	payload, err := json.Marshal(eventRequest{Message: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := h.event(context.Background(), "emit", payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case msg := <-recorder.Events:
+		if !strings.Contains(msg, "hi") {
+			t.Fatalf("expected the recorded event to contain the message, got %q", msg)
+		}
+	default:
+		t.Fatal("expected an event to be recorded")
+	}
+}
+
+func TestHost_Invoke_RejectsUnknownBindingAndNamespace(t *testing.T) {
+	h := &Host{}
+
+	if _, err := h.invoke(context.Background(), "wrong-binding", "get", "resource", nil); err == nil {
+		t.Fatal("expected an error for an unrecognised binding")
+	}
+
+	if _, err := h.invoke(context.Background(), binding, "unknown", "op", nil); err == nil {
+		t.Fatal("expected an error for an unrecognised namespace")
+	}
+}
@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func nonNilWriter(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+
+	return w
+}
+
+func writeWASIInput(dir string, payload []byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, WASIInputFile), payload, 0o644); err != nil {
+		return fmt.Errorf("failed to write WASI module input: %w", err)
+	}
+
+	return nil
+}
+
+func readWASIOutput(dir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, WASIOutputFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read WASI module output: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+// Kind identifies the calling convention a middleware module expects.
+type Kind string
+
+const (
+	// KindWAPC is a waPC module invoked through the waPC guest/host
+	// protocol, i.e. an exported "handler" function taking the rendered
+	// values as its payload.
+	KindWAPC Kind = "wapc"
+
+	// KindWASI is a plain WASI module invoked by calling a fixed
+	// entrypoint export directly, with the values payload written to a
+	// well-known file inside the module's mounted directory rather than
+	// passed as a function argument.
+	KindWASI Kind = "wasi"
+)
+
+// WASIEntrypoint is the export WASI middleware modules must provide.
+// The module receives no arguments; it reads its input from
+// "/data/.wasm-input.json" inside its mounted directory and, if it wants to
+// produce output, writes it to "/data/.wasm-output.json".
+const WASIEntrypoint = "_start"
+
+// WASIInputFile and WASIOutputFile are the well-known paths, relative to a
+// WASI module's mounted directory, used to pass values in and results out
+// since WASI's "_start" takes no arguments.
+const (
+	WASIInputFile  = ".wasm-input.json"
+	WASIOutputFile = ".wasm-output.json"
+)
+
+// Source is a middleware module's bytecode together with the calling
+// convention it expects.
+type Source struct {
+	Kind Kind
+	Data []byte
+}
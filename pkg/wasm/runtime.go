@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wasm runs ocm-controller middleware modules in a sandboxed
+// wazero runtime. It replaces ad-hoc engine setup that used to live
+// directly in ResourceReconciler with a single place that enforces
+// resource limits, a namespaced host ABI (see Host), and support for both
+// waPC modules and plain WASI modules.
+//
+// TODO(cpu-limit): Config only bounds wall-clock time (Timeout) and
+// memory (MaxMemoryPages). wazero has no built-in instruction/fuel
+// metering, so there is currently no way to bound the CPU time a module
+// burns within its wall-clock budget - a module that spins for the full
+// Timeout on every invocation is only ever stopped by the clock, not by
+// any CPU accounting.
+//
+// TODO(wasi-http): the WASI runtime provides only wasi_snapshot_preview1,
+// and the waPC runtime only the ocm.software host ABI (see Host). A module that
+// wants outbound HTTP has no import to reach it - no wasi-http support
+// exists here yet.
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	wazeroimports "github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/wapc/wapc-go"
+	wazeroEngine "github.com/wapc/wapc-go/engines/wazero"
+)
+
+// HostFunc is the waPC host callback signature, unchanged from makeHost's
+// previous inline definition so existing host implementations keep working.
+type HostFunc func(ctx context.Context, binding, namespace, operation string, payload []byte) ([]byte, error)
+
+// Runtime executes a single middleware Source against a mounted directory,
+// enforcing the limits in Config. Callers create one Runtime per reconcile
+// and may Invoke several modules on it in turn; it is not meant to be
+// reused across reconciles.
+type Runtime struct {
+	cfg Config
+	dir string
+}
+
+// New returns a Runtime that mounts dir as "/data" for the module and
+// enforces cfg's limits. If cfg is the zero value, DefaultConfig is used.
+func New(dir string, cfg Config) *Runtime {
+	if cfg.Timeout == 0 && cfg.MaxMemoryPages == 0 {
+		cfg = DefaultConfig()
+	}
+
+	return &Runtime{cfg: cfg, dir: dir}
+}
+
+// Invoke runs src, passing payload to it and returning whatever it
+// produces. For a waPC module this calls the "handler" export with
+// payload as its argument; for a WASI module it writes payload to
+// WASIInputFile, calls WASIEntrypoint, and reads WASIOutputFile back (an
+// empty result is not an error - not every module produces output).
+func (r *Runtime) Invoke(ctx context.Context, host HostFunc, src Source, payload []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	switch src.Kind {
+	case KindWAPC, "":
+		return r.invokeWAPC(ctx, host, src.Data, payload)
+	case KindWASI:
+		return r.invokeWASI(ctx, src.Data, payload)
+	default:
+		return nil, fmt.Errorf("unsupported middleware module kind: %q", src.Kind)
+	}
+}
+
+func (r *Runtime) invokeWAPC(ctx context.Context, host HostFunc, data, payload []byte) ([]byte, error) {
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if r.cfg.MaxMemoryPages > 0 {
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(r.cfg.MaxMemoryPages)
+	}
+
+	engine := wazeroEngine.NewEngine(runtimeConfig)
+
+	module, err := engine.New(ctx, wapc.HostCallHandler(host), data, &wapc.ModuleConfig{
+		Logger: wapc.PrintlnLogger,
+		Stdout: nonNilWriter(r.cfg.Stdout),
+		Stderr: nonNilWriter(r.cfg.Stderr),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load waPC module: %w", err)
+	}
+	defer module.Close(ctx)
+
+	module.(*wazeroEngine.Module).WithConfig(func(config wazero.ModuleConfig) wazero.ModuleConfig {
+		fsConfig := wazero.NewFSConfig().WithDirMount(r.dir, "/data")
+
+		return config.WithFSConfig(fsConfig).WithSysWalltime()
+	})
+
+	instance, err := module.Instantiate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate waPC module: %w", err)
+	}
+	defer instance.Close(ctx)
+
+	return instance.Invoke(ctx, "handler", payload)
+}
+
+func (r *Runtime) invokeWASI(ctx context.Context, data, payload []byte) ([]byte, error) {
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if r.cfg.MaxMemoryPages > 0 {
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(r.cfg.MaxMemoryPages)
+	}
+
+	rt := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer rt.Close(ctx)
+
+	if _, err := wazeroimports.Instantiate(ctx, rt); err != nil {
+		return nil, fmt.Errorf("failed to instantiate WASI snapshot preview1: %w", err)
+	}
+
+	if err := writeWASIInput(r.dir, payload); err != nil {
+		return nil, err
+	}
+
+	compiled, err := rt.CompileModule(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile WASI module: %w", err)
+	}
+
+	moduleConfig := wazero.NewModuleConfig().
+		WithStdout(nonNilWriter(r.cfg.Stdout)).
+		WithStderr(nonNilWriter(r.cfg.Stderr)).
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(r.dir, "/data"))
+
+	if _, err := rt.InstantiateModule(ctx, compiled, moduleConfig); err != nil {
+		// A WASI "_start" that calls proc_exit(0) surfaces here as a
+		// sys.ExitError with code zero; that is a normal, successful run.
+		var exitErr interface{ ExitCode() uint32 }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+			return readWASIOutput(r.dir)
+		}
+
+		return nil, fmt.Errorf("failed to run WASI module: %w", err)
+	}
+
+	return readWASIOutput(r.dir)
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// spinWASI is a hand-assembled WASM module exporting "_start" as an
+// unconditional infinite loop ("(loop br 0)"). It takes no WASI imports, so
+// it runs under invokeWASI without needing a fake filesystem. It exists
+// purely to prove that Runtime.Invoke's timeout actually tears down a
+// module stuck in a tight loop, rather than merely giving up on waiting for
+// a result that keeps running in the background.
+var spinWASI = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic, version
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: func ()->()
+	0x03, 0x02, 0x01, 0x00, // function section: func0 : type0
+	0x07, 0x0a, 0x01, 0x06, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x00, 0x00, // export "_start" func0
+	0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b, // code: loop { br 0 }
+}
+
+func TestRuntime_Invoke_WASI_TimeoutKillsSpinningModule(t *testing.T) {
+	dir := t.TempDir()
+
+	r := New(dir, Config{Timeout: 200 * time.Millisecond})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Invoke(context.Background(), nil, Source{Kind: KindWASI, Data: spinWASI}, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the timeout to surface as an error, got nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("spinning module was not interrupted by the invocation timeout")
+	}
+}
+
+func TestNonNilWriter(t *testing.T) {
+	if w := nonNilWriter(nil); w == nil {
+		t.Fatal("expected a non-nil writer")
+	}
+
+	if w := nonNilWriter(os.Stdout); w != os.Stdout {
+		t.Fatal("expected the given writer to be returned unchanged")
+	}
+}